@@ -0,0 +1,71 @@
+/*
+Copyright 2015 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bigtable
+
+import (
+	"context"
+
+	btadmin "cloud.google.com/go/bigtable/admin/apiv2"
+	btapb "cloud.google.com/go/bigtable/admin/apiv2/adminpb"
+	"google.golang.org/api/option"
+)
+
+// AdminClient is a client type for performing administrative operations
+// within a specific instance.
+type AdminClient struct {
+	c        *btadmin.BigtableTableAdminClient
+	project  string
+	instance string
+}
+
+// NewAdminClient creates a new AdminClient for a given project and instance.
+func NewAdminClient(ctx context.Context, project, instance string, opts ...option.ClientOption) (*AdminClient, error) {
+	c, err := btadmin.NewBigtableTableAdminClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &AdminClient{c: c, project: project, instance: instance}, nil
+}
+
+// Close closes the AdminClient.
+func (ac *AdminClient) Close() error {
+	return ac.c.Close()
+}
+
+func (ac *AdminClient) instancePrefix() string {
+	return "projects/" + ac.project + "/instances/" + ac.instance
+}
+
+// CreateTable creates a new table in the instance.
+func (ac *AdminClient) CreateTable(ctx context.Context, table string) error {
+	_, err := ac.c.CreateTable(ctx, &btapb.CreateTableRequest{
+		Parent:  ac.instancePrefix(),
+		TableId: table,
+	})
+	return err
+}
+
+// CreateColumnFamily creates a new column family in a table.
+func (ac *AdminClient) CreateColumnFamily(ctx context.Context, table, family string) error {
+	_, err := ac.c.ModifyColumnFamilies(ctx, &btapb.ModifyColumnFamiliesRequest{
+		Name: ac.instancePrefix() + "/tables/" + table,
+		Modifications: []*btapb.ModifyColumnFamiliesRequest_Modification{{
+			Id:  family,
+			Mod: &btapb.ModifyColumnFamiliesRequest_Modification_Create{Create: &btapb.ColumnFamily{}},
+		}},
+	})
+	return err
+}
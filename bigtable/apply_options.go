@@ -0,0 +1,47 @@
+/*
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bigtable
+
+import "time"
+
+// ApplyOption is an option passed to Apply or ApplyBulk to configure how a
+// mutation is applied.
+type ApplyOption interface {
+	applyOption(*applySettings)
+}
+
+// applySettings holds the configuration gathered from a set of ApplyOptions.
+type applySettings struct {
+	attemptTimeout time.Duration
+	hedging        *HedgingPolicy
+	retryPolicy    RetryPolicy
+	checkpoint     BulkCheckpoint
+}
+
+// checkpointOption backs WithCheckpoint. Unlike attemptOption and
+// retryPolicyOption, it only applies to ApplyBulkStream, so it implements
+// ApplyOption alone rather than the ApplyOption/ReadOption pair.
+type checkpointOption struct {
+	checkpoint BulkCheckpoint
+}
+
+func (o checkpointOption) applyOption(s *applySettings) { s.checkpoint = o.checkpoint }
+
+// WithCheckpoint returns an option that has ApplyBulkStream persist commit
+// progress to cp, skipping rows cp already reports as committed.
+func WithCheckpoint(cp BulkCheckpoint) ApplyOption {
+	return checkpointOption{checkpoint: cp}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bigtable
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/status"
+)
+
+// HedgingPolicy configures hedged requests: a second, duplicate attempt
+// fired after Delay if the first attempt has not yet completed. The first
+// attempt to complete wins; the other is cancelled. Hedging is only applied
+// to idempotent operations, since the server may observe both attempts.
+type HedgingPolicy struct {
+	// Delay is how long to wait for the first attempt before firing the
+	// hedged attempt. A Delay of zero or less disables hedging.
+	Delay time.Duration
+}
+
+// attemptOption is implemented by options that affect individual RPC
+// attempts, as opposed to the overall retry loop. It backs both
+// WithAttemptTimeout and WithHedging, which apply identically whether the
+// call is Apply, ApplyBulk or ReadRows.
+type attemptOption struct {
+	attemptTimeout time.Duration
+	hedging        *HedgingPolicy
+}
+
+func (o attemptOption) applyOption(s *applySettings) {
+	if o.attemptTimeout > 0 {
+		s.attemptTimeout = o.attemptTimeout
+	}
+	if o.hedging != nil {
+		s.hedging = o.hedging
+	}
+}
+
+func (o attemptOption) readOption(s *readSettings) {
+	if o.attemptTimeout > 0 {
+		s.attemptTimeout = o.attemptTimeout
+	}
+	if o.hedging != nil {
+		s.hedging = o.hedging
+	}
+}
+
+// WithAttemptTimeout returns an option that bounds each individual RPC
+// attempt to d, independent of the overall context deadline. It can be
+// passed to Apply, ApplyBulk and ReadRows.
+func WithAttemptTimeout(d time.Duration) interface {
+	ApplyOption
+	ReadOption
+} {
+	return attemptOption{attemptTimeout: d}
+}
+
+// WithHedging returns an option that enables hedged requests following
+// policy. It can be passed to Apply, ApplyBulk and ReadRows.
+func WithHedging(policy HedgingPolicy) interface {
+	ApplyOption
+	ReadOption
+} {
+	p := policy
+	return attemptOption{hedging: &p}
+}
+
+// attemptResult is the outcome of a single hedged or unhedged attempt.
+type attemptResult[T any] struct {
+	val T
+	err error
+}
+
+// runAttempt runs call once, respecting attemptTimeout and, if hedge is set
+// and idempotent is true, firing a second attempt after hedge.Delay and
+// returning whichever finishes first. The loser (if any) is cancelled once
+// runAttempt returns.
+func runAttempt[T any](ctx context.Context, attemptTimeout time.Duration, hedge *HedgingPolicy, idempotent bool, call func(context.Context) (T, error)) (T, error) {
+	run := func(actx context.Context) attemptResult[T] {
+		if attemptTimeout > 0 {
+			var cancel context.CancelFunc
+			actx, cancel = context.WithTimeout(actx, attemptTimeout)
+			defer cancel()
+		}
+		v, err := call(actx)
+		return attemptResult[T]{v, err}
+	}
+
+	if hedge == nil || hedge.Delay <= 0 || !idempotent {
+		r := run(ctx)
+		return r.val, r.err
+	}
+
+	hctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch := make(chan attemptResult[T], 2)
+	go func() { ch <- run(hctx) }()
+	timer := time.NewTimer(hedge.Delay)
+	defer timer.Stop()
+	select {
+	case r := <-ch:
+		return r.val, r.err
+	case <-timer.C:
+		go func() { ch <- run(hctx) }()
+		r := <-ch
+		return r.val, r.err
+	case <-ctx.Done():
+		var zero T
+		return zero, status.FromContextError(ctx.Err()).Err()
+	}
+}
@@ -0,0 +1,680 @@
+/*
+Copyright 2015 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bigtable provides a client for reading and writing data to Cloud
+// Bigtable.
+package bigtable
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	btapiv2 "cloud.google.com/go/bigtable/apiv2"
+	btpb "cloud.google.com/go/bigtable/apiv2/bigtablepb"
+	gax "github.com/googleapis/gax-go/v2"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ClientConfig has configuration for the client.
+type ClientConfig struct {
+	// MetricsProvider supplies the recorders used to export client-side
+	// metrics. If nil, no metrics are recorded.
+	MetricsProvider MetricsProvider
+
+	// AppProfile is the app profile to use for requests, or "" for the
+	// instance's default app profile.
+	AppProfile string
+
+	// DisableRetryInfo disables honoring google.rpc.RetryInfo error details
+	// returned by the server when computing retry backoff. It mirrors the
+	// DISABLE_RETRY_INFO environment variable; if either is set, RetryInfo
+	// is ignored and the client falls back to its own exponential backoff.
+	// It only affects the built-in policy used when RetryPolicy is nil; a
+	// RetryPolicy set here or via WithRetryPolicy is not affected by it.
+	DisableRetryInfo bool
+
+	// RetryPolicy determines which errors are retried, how long to wait
+	// between attempts, and which Mutations are safe to retry or hedge. If
+	// nil, the client's built-in policy is used (equivalent to
+	// DefaultRetryPolicy, except that it also honors DisableRetryInfo). A
+	// call's RetryPolicy can be overridden individually with
+	// WithRetryPolicy.
+	RetryPolicy RetryPolicy
+}
+
+// Client is a client for reading and writing data to tables in an instance.
+type Client struct {
+	client           *btapiv2.Client
+	project          string
+	instance         string
+	appProfile       string
+	metricsProvider  MetricsProvider
+	disableRetryInfo bool
+	retryPolicy      RetryPolicy
+}
+
+// NewClient creates a new Client for a given project and instance.
+func NewClient(ctx context.Context, project, instance string, opts ...option.ClientOption) (*Client, error) {
+	return NewClientWithConfig(ctx, project, instance, ClientConfig{}, opts...)
+}
+
+// NewClientWithConfig creates a new Client for a given project and instance
+// using the given configuration.
+func NewClientWithConfig(ctx context.Context, project, instance string, config ClientConfig, opts ...option.ClientOption) (*Client, error) {
+	c, err := btapiv2.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	disableRetryInfo := config.DisableRetryInfo
+	if v, err := strconv.ParseBool(os.Getenv("DISABLE_RETRY_INFO")); err == nil {
+		disableRetryInfo = disableRetryInfo || v
+	} else if os.Getenv("DISABLE_RETRY_INFO") != "" {
+		// Any non-empty, non-boolean value also disables RetryInfo.
+		disableRetryInfo = true
+	}
+	client := &Client{
+		client:           c,
+		project:          project,
+		instance:         instance,
+		appProfile:       config.AppProfile,
+		metricsProvider:  config.MetricsProvider,
+		disableRetryInfo: disableRetryInfo,
+		retryPolicy:      config.RetryPolicy,
+	}
+	if client.retryPolicy == nil {
+		client.retryPolicy = clientRetryPolicy{c: client}
+	}
+	return client, nil
+}
+
+// Close closes the Client.
+func (c *Client) Close() error {
+	return c.client.Close()
+}
+
+func (c *Client) fullTableName(table string) string {
+	return "projects/" + c.project + "/instances/" + c.instance + "/tables/" + table
+}
+
+// Open opens a table.
+func (c *Client) Open(table string) *Table {
+	return &Table{c: c, table: table}
+}
+
+// Table is a reference to a table.
+type Table struct {
+	c     *Client
+	table string
+}
+
+// retryableCodes are status codes that are safe to retry for idempotent
+// operations without further inspection.
+var retryableCodes = map[codes.Code]bool{
+	codes.Unavailable: true,
+	codes.Aborted:     true,
+}
+
+// retryableInternalErrMsgs holds substrings of codes.Internal error messages
+// that are known to be transient (e.g. broken HTTP/2 streams) and therefore
+// safe to retry, even though codes.Internal is not retryable in general.
+var retryableInternalErrMsgs = []string{
+	"stream terminated by RST_STREAM",
+	"insufficient quota to perform this operation",
+	"connection reset by peer",
+}
+
+func isRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	if retryableCodes[st.Code()] {
+		return true
+	}
+	if st.Code() == codes.Internal {
+		for _, msg := range retryableInternalErrMsgs {
+			if msg != "" && strings.Contains(st.Message(), msg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// retryInfoDelay inspects err for an attached google.rpc.RetryInfo detail
+// and, if present, returns the server-requested retry delay. A delay of
+// zero means the caller should retry immediately.
+func retryInfoDelay(err error) (time.Duration, bool) {
+	st, ok := status.FromError(err)
+	if !ok {
+		return 0, false
+	}
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok && ri.GetRetryDelay() != nil {
+			return ri.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+// retryPolicyFor returns the RetryPolicy that should govern a call: override
+// if non-nil (set via WithRetryPolicy), otherwise the table's client's
+// policy.
+func (t *Table) retryPolicyFor(override RetryPolicy) RetryPolicy {
+	if override != nil {
+		return override
+	}
+	return t.c.retryPolicy
+}
+
+// Apply applies a Mutation to a specific row.
+func (t *Table) Apply(ctx context.Context, row string, m *Mutation, opts ...ApplyOption) error {
+	var settings applySettings
+	for _, opt := range opts {
+		opt.applyOption(&settings)
+	}
+	policy := t.retryPolicyFor(settings.retryPolicy)
+
+	if m.isConditional() {
+		req := &btpb.CheckAndMutateRowRequest{
+			TableName:       t.c.fullTableName(t.table),
+			AppProfileId:    t.c.appProfile,
+			RowKey:          []byte(row),
+			PredicateFilter: m.condFilter.proto(),
+			TrueMutations:   m.mTrue.ops,
+		}
+		if m.mFalse != nil {
+			req.FalseMutations = m.mFalse.ops
+		}
+		// Conditional mutations are not idempotent in general (the
+		// predicate may no longer match on retry), so we make a single,
+		// unhedged attempt bounded only by settings.attemptTimeout and
+		// surface whatever error the server returns.
+		_, err := runAttempt(ctx, settings.attemptTimeout, nil, false, func(actx context.Context) (struct{}, error) {
+			_, err := t.c.client.CheckAndMutateRow(actx, req)
+			return struct{}{}, err
+		})
+		return err
+	}
+
+	req := &btpb.MutateRowRequest{
+		TableName:    t.c.fullTableName(t.table),
+		AppProfileId: t.c.appProfile,
+		RowKey:       []byte(row),
+		Mutations:    m.ops,
+	}
+	for attempt := 0; ; attempt++ {
+		_, err := runAttempt(ctx, settings.attemptTimeout, settings.hedging, policy.IsIdempotent(m), func(actx context.Context) (struct{}, error) {
+			_, err := t.c.client.MutateRow(actx, req)
+			return struct{}{}, err
+		})
+		if err == nil {
+			return nil
+		}
+		retry, delay := policy.ShouldRetry(err, attempt)
+		if !retry {
+			return err
+		}
+		if serr := gax.Sleep(ctx, delay); serr != nil {
+			return status.FromContextError(serr).Err()
+		}
+	}
+}
+
+type bulkMutsEntry struct {
+	idx int
+	row string
+	mut *Mutation
+}
+
+// bulkResult is a terminal (non-retryable) per-entry error keyed by its
+// position in the original rowKeys/muts slices passed to ApplyBulk.
+type bulkResult struct {
+	idx int
+	err error
+}
+
+// bulkAttemptResult is the outcome of a single MutateRows attempt: which
+// entries need to be retried, and which failed terminally. retryDelay is the
+// policy-provided backoff for the first retryable entry error seen, used
+// when entries (rather than the whole batch RPC) fail.
+type bulkAttemptResult struct {
+	retry          []*bulkMutsEntry
+	terminal       []bulkResult
+	retryDelay     time.Duration
+	haveRetryDelay bool
+}
+
+// ApplyBulk applies multiple Mutations, one per row, in a single set of RPCs.
+// It returns one error per Mutation, so if a single row fails it is
+// indicated in the corresponding entry of the returned slice. If a bulk-wide
+// error occurs, err is returned and errs is nil.
+func (t *Table) ApplyBulk(ctx context.Context, rowKeys []string, muts []*Mutation, opts ...ApplyOption) (errs []error, err error) {
+	if len(rowKeys) != len(muts) {
+		return nil, fmt.Errorf("bigtable: mismatched rowKeys and mutation array lengths: %d, %d", len(rowKeys), len(muts))
+	}
+	if len(rowKeys) == 0 {
+		return nil, nil
+	}
+
+	var settings applySettings
+	for _, opt := range opts {
+		opt.applyOption(&settings)
+	}
+	policy := t.retryPolicyFor(settings.retryPolicy)
+
+	pending := make([]*bulkMutsEntry, len(rowKeys))
+	for i, row := range rowKeys {
+		pending[i] = &bulkMutsEntry{idx: i, row: row, mut: muts[i]}
+	}
+	results := make([]error, len(rowKeys))
+
+	for attempt := 0; len(pending) > 0; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+		req := &btpb.MutateRowsRequest{
+			TableName:    t.c.fullTableName(t.table),
+			AppProfileId: t.c.appProfile,
+			Entries:      make([]*btpb.MutateRowsRequest_Entry, len(pending)),
+		}
+		for i, e := range pending {
+			req.Entries[i] = &btpb.MutateRowsRequest_Entry{RowKey: []byte(e.row), Mutations: e.mut.ops}
+		}
+
+		// The whole outstanding batch is idempotent to hedge only if every
+		// entry in it is; a non-idempotent mutation could otherwise be
+		// applied twice by the server.
+		batchIdempotent := true
+		for _, e := range pending {
+			if !policy.IsIdempotent(e.mut) {
+				batchIdempotent = false
+				break
+			}
+		}
+
+		outcome, batchErr := runAttempt(ctx, settings.attemptTimeout, settings.hedging, batchIdempotent,
+			func(actx context.Context) (bulkAttemptResult, error) {
+				stream, serr := t.c.client.MutateRows(actx, req)
+				if serr != nil {
+					return bulkAttemptResult{}, serr
+				}
+				var out bulkAttemptResult
+				for {
+					res, rerr := stream.Recv()
+					if rerr == io.EOF {
+						return out, nil
+					}
+					if rerr != nil {
+						return bulkAttemptResult{}, rerr
+					}
+					for _, entryRes := range res.Entries {
+						entry := pending[entryRes.Index]
+						if codes.Code(entryRes.Status.GetCode()) == codes.OK {
+							continue
+						}
+						entryErr := status.FromProto(entryRes.Status).Err()
+						retry, delay := policy.ShouldRetry(entryErr, attempt)
+						if retry && policy.IsIdempotent(entry.mut) {
+							out.retry = append(out.retry, entry)
+							if !out.haveRetryDelay {
+								out.retryDelay = delay
+								out.haveRetryDelay = true
+							}
+							continue
+						}
+						out.terminal = append(out.terminal, bulkResult{idx: entry.idx, err: entryErr})
+					}
+				}
+			})
+
+		if batchErr != nil {
+			retry, delay := policy.ShouldRetry(batchErr, attempt)
+			if !retry {
+				return nil, batchErr
+			}
+			if werr := gax.Sleep(ctx, delay); werr != nil {
+				return nil, status.FromContextError(werr).Err()
+			}
+			continue
+		}
+
+		// Only the winning attempt's outcome (hedging may have run two
+		// concurrent attempts against the same pending slice) is applied.
+		for _, r := range outcome.terminal {
+			results[r.idx] = r.err
+		}
+		pending = outcome.retry
+		if len(pending) > 0 {
+			delay := outcome.retryDelay
+			if !outcome.haveRetryDelay {
+				delay = backoffForAttempt(attempt)
+			}
+			if werr := gax.Sleep(ctx, delay); werr != nil {
+				return nil, status.FromContextError(werr).Err()
+			}
+		}
+	}
+
+	for _, e := range results {
+		if e != nil {
+			return results, nil
+		}
+	}
+	return nil, nil
+}
+
+// ReadItem holds a single cell's data.
+type ReadItem struct {
+	Row, Column string
+	Timestamp   Timestamp
+	Value       []byte
+}
+
+// Row is a row of data from a table, keyed by column family.
+type Row map[string][]ReadItem
+
+// Key returns the row's key.
+func (r Row) Key() string {
+	for _, items := range r {
+		if len(items) > 0 {
+			return items[0].Row
+		}
+	}
+	return ""
+}
+
+// ReadOption is an option passed to ReadRows.
+type ReadOption interface {
+	readOption(*readSettings)
+}
+
+type readSettings struct {
+	limit          int64
+	reverse        bool
+	attemptTimeout time.Duration
+	hedging        *HedgingPolicy
+	retryPolicy    RetryPolicy
+	onStats        func(ReadStats)
+}
+
+type limitRows int64
+
+func (l limitRows) readOption(rs *readSettings) { rs.limit = int64(l) }
+
+// LimitRows returns a ReadOption that will limit the number of rows to be
+// read.
+func LimitRows(limit int64) ReadOption { return limitRows(limit) }
+
+type reverseScan struct{}
+
+func (reverseScan) readOption(rs *readSettings) { rs.reverse = true }
+
+// ReverseScan returns a ReadOption that will cause rows to be streamed back
+// in reverse lexiographic order of the row keys, rather than the standard
+// forward lexiographic order.
+func ReverseScan() ReadOption { return reverseScan{} }
+
+// ReadRow reads a single row.
+func (t *Table) ReadRow(ctx context.Context, row string, opts ...ReadOption) (Row, error) {
+	var result Row
+	err := t.ReadRows(ctx, RowList{row}, func(r Row) bool {
+		result = r
+		return true
+	}, opts...)
+	return result, err
+}
+
+// ReadRows reads rows from a table, invoking f for each row. If f returns
+// false, the stream is stopped. f owns its argument, and f is called
+// serially in order by row key.
+func (t *Table) ReadRows(ctx context.Context, arg RowSet, f func(Row) bool, opts ...ReadOption) error {
+	var settings readSettings
+	for _, opt := range opts {
+		opt.readOption(&settings)
+	}
+	policy := t.retryPolicyFor(settings.retryPolicy)
+
+	state := newReadState(settings.limit, settings.reverse)
+	if settings.onStats != nil {
+		defer func() { settings.onStats(state.stats()) }()
+	}
+
+	for {
+		state.attempts++
+		if err := ctx.Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		req := &btpb.ReadRowsRequest{
+			TableName:    t.c.fullTableName(t.table),
+			AppProfileId: t.c.appProfile,
+			Rows:         arg.proto(),
+			Reversed:     settings.reverse,
+		}
+		if state.rowsLimit() > 0 {
+			req.RowsLimit = state.rowsLimit()
+		}
+
+		// startReadRows races two ReadRows attempts (if hedging is enabled)
+		// and commits to whichever produces its first message first,
+		// cancelling the other. Unlike Apply/ApplyBulk, the winning
+		// attempt's context must stay alive for as long as its stream is
+		// being drained below, so cancelAttempt is only called once this
+		// iteration is fully done with it.
+		stop, retErr := func() (bool, error) {
+			ra, cancelAttempt, streamErr := t.startReadRows(ctx, req, settings.attemptTimeout, settings.hedging)
+			defer cancelAttempt()
+
+			if streamErr == nil {
+				var cr chunkReader
+				stream := ra.stream
+				res := ra.first
+				rerr := ra.firstErr
+				for rerr != io.EOF {
+					if rerr != nil {
+						streamErr = rerr
+						break
+					}
+					if len(res.LastScannedRowKey) > 0 {
+						state.lastRowKey = string(res.LastScannedRowKey)
+					}
+					rows, _ := cr.add(res)
+					for _, r := range rows {
+						state.recordRow(r.Key())
+						if !f(r) {
+							return true, nil
+						}
+					}
+					res, rerr = stream.Recv()
+				}
+				if streamErr == nil {
+					// The server closed the stream cleanly: the scan (or
+					// the requested row limit) is complete.
+					return true, nil
+				}
+			}
+
+			retry, delay := policy.ShouldRetry(streamErr, state.attempts-1)
+			if !retry {
+				return true, streamErr
+			}
+			if settings.limit > 0 && state.rowsLimit() == 0 {
+				// Every row allowed by LimitRows has been delivered.
+				return true, nil
+			}
+			narrowed, ok := state.narrow(arg)
+			arg = narrowed
+			if !ok {
+				return true, nil
+			}
+			if werr := gax.Sleep(ctx, delay); werr != nil {
+				return true, status.FromContextError(werr).Err()
+			}
+			return false, nil
+		}()
+		if stop {
+			return retErr
+		}
+	}
+}
+
+// startReadRows opens a ReadRows stream for req and receives its first
+// message. If hedge is set, it races a second, duplicate attempt fired
+// after hedge.Delay and commits to whichever attempt produces a first
+// message (or error) first; the other attempt's context is cancelled
+// immediately, interrupting it even if it is blocked in Recv. The returned
+// cancel func must be called once the caller is done consuming the
+// returned stream.
+func (t *Table) startReadRows(ctx context.Context, req *btpb.ReadRowsRequest, attemptTimeout time.Duration, hedge *HedgingPolicy) (readAttempt, context.CancelFunc, error) {
+	type outcome struct {
+		ra  readAttempt
+		err error
+	}
+	// newAttemptCtx derives a cancellable context for one attempt, owned by
+	// the caller rather than by launch, so a loser can be cancelled the
+	// instant a winner is chosen instead of waiting for its launch call
+	// (which may be blocked in Recv) to return.
+	newAttemptCtx := func() (context.Context, context.CancelFunc) {
+		if attemptTimeout > 0 {
+			return context.WithTimeout(ctx, attemptTimeout)
+		}
+		return context.WithCancel(ctx)
+	}
+	launch := func(actx context.Context) outcome {
+		s, serr := t.c.client.ReadRows(actx, req)
+		if serr != nil {
+			return outcome{err: serr}
+		}
+		first, rerr := s.Recv()
+		if rerr != nil && rerr != io.EOF {
+			return outcome{err: rerr}
+		}
+		return outcome{ra: readAttempt{stream: s, first: first, firstErr: rerr}}
+	}
+
+	actx, cancel := newAttemptCtx()
+	if hedge == nil || hedge.Delay <= 0 {
+		o := launch(actx)
+		if o.err != nil {
+			cancel()
+			return readAttempt{}, func() {}, o.err
+		}
+		return o.ra, cancel, nil
+	}
+
+	primary := make(chan outcome, 1)
+	go func() { primary <- launch(actx) }()
+
+	timer := time.NewTimer(hedge.Delay)
+	defer timer.Stop()
+
+	select {
+	case o := <-primary:
+		if o.err != nil {
+			cancel()
+			return readAttempt{}, func() {}, o.err
+		}
+		return o.ra, cancel, nil
+	case <-ctx.Done():
+		cancel()
+		return readAttempt{}, func() {}, status.FromContextError(ctx.Err()).Err()
+	case <-timer.C:
+	}
+
+	hactx, hcancel := newAttemptCtx()
+	hedgeCh := make(chan outcome, 1)
+	go func() { hedgeCh <- launch(hactx) }()
+
+	select {
+	case o := <-primary:
+		hcancel() // interrupt the hedge attempt's Recv immediately
+		go func() { <-hedgeCh }()
+		if o.err != nil {
+			cancel()
+			return readAttempt{}, func() {}, o.err
+		}
+		return o.ra, cancel, nil
+	case o := <-hedgeCh:
+		cancel() // interrupt the primary attempt's Recv immediately
+		go func() { <-primary }()
+		if o.err != nil {
+			hcancel()
+			return readAttempt{}, func() {}, o.err
+		}
+		return o.ra, hcancel, nil
+	}
+}
+
+// readAttempt is the outcome of opening a ReadRows stream and receiving its
+// first message, used to let hedged attempts race on "first byte" rather
+// than on the whole (potentially very long) stream.
+type readAttempt struct {
+	stream   btpb.Bigtable_ReadRowsClient
+	first    *btpb.ReadRowsResponse
+	firstErr error
+}
+
+// chunkReader accumulates ReadRowsResponse chunks into complete Rows. It
+// supports only the simple single-cell-per-chunk shape used by this
+// client's fake-server based tests, plus ResetRow; cells split across
+// several chunks are not merged.
+type chunkReader struct {
+	row    Row
+	rowKey string
+}
+
+func (cr *chunkReader) add(res *btpb.ReadRowsResponse) ([]Row, error) {
+	var out []Row
+	for _, c := range res.Chunks {
+		if len(c.RowKey) > 0 {
+			cr.rowKey = string(c.RowKey)
+		}
+		if cr.row == nil {
+			cr.row = make(Row)
+		}
+		if c.FamilyName != nil {
+			family := c.FamilyName.GetValue()
+			item := ReadItem{
+				Row:       cr.rowKey,
+				Column:    family + ":" + string(c.Qualifier.GetValue()),
+				Timestamp: Timestamp(c.TimestampMicros),
+				Value:     c.Value,
+			}
+			cr.row[family] = append(cr.row[family], item)
+		}
+		if c.GetResetRow() {
+			cr.row = nil
+			cr.rowKey = ""
+			continue
+		}
+		if c.GetCommitRow() {
+			out = append(out, cr.row)
+			cr.row = nil
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2015 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bigtable
+
+import "google.golang.org/grpc/status"
+
+// disableMetricsConfig is a ClientConfig that opts out of metrics, for use
+// by tests that don't want to exercise the metrics pipeline.
+var disableMetricsConfig = ClientConfig{MetricsProvider: NoopMetricsProvider{}}
+
+// equalErrs reports whether got and want represent the same gRPC status
+// error, ignoring differences that don't round-trip through the wire (e.g.
+// wrapped error types).
+func equalErrs(want, got error) bool {
+	ws, wok := status.FromError(want)
+	gs, gok := status.FromError(got)
+	if wok != gok {
+		return false
+	}
+	if !wok {
+		return want == got
+	}
+	return ws.Code() == gs.Code() && ws.Message() == gs.Message()
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bigtable
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// bulkStreamChunkSize is the number of entries ApplyBulkStream batches into
+// a single underlying ApplyBulk call. A var, rather than a const, so tests
+// can shrink it to exercise multi-chunk behavior without huge fixtures.
+var bulkStreamChunkSize = 1000
+
+// BulkEntry is a single row/mutation pair read from the channel passed to
+// ApplyBulkStream.
+type BulkEntry struct {
+	RowKey   string
+	Mutation *Mutation
+}
+
+// BulkCheckpoint persists which row keys ApplyBulkStream has successfully
+// committed, so a long-running streaming job can resume after a process
+// restart without re-applying rows it already finished. NewFileBulkCheckpoint
+// provides a file-backed implementation.
+type BulkCheckpoint interface {
+	// IsCommitted reports whether rowKey was already committed in a
+	// previous run.
+	IsCommitted(rowKey string) (bool, error)
+
+	// MarkCommitted records that rowKey has been successfully applied.
+	MarkCommitted(rowKey string) error
+}
+
+// ApplyBulkStream reads BulkEntry values from rowsCh and applies them in
+// chunks, the same way repeated calls to ApplyBulk would. If opts includes
+// WithCheckpoint, entries already marked committed in the checkpoint are
+// skipped, and newly committed entries are recorded in it as each chunk
+// completes; this allows a streaming job that is killed partway through to
+// resume, on restart, with the same checkpoint and replay only the entries
+// that were not yet committed. ApplyBulkStream returns as soon as rowsCh is
+// closed and any final chunk has been applied, or the first terminal error.
+func (t *Table) ApplyBulkStream(ctx context.Context, rowsCh <-chan BulkEntry, opts ...ApplyOption) error {
+	var settings applySettings
+	for _, opt := range opts {
+		opt.applyOption(&settings)
+	}
+	cp := settings.checkpoint
+
+	var rowKeys []string
+	var muts []*Mutation
+	flush := func() error {
+		if len(rowKeys) == 0 {
+			return nil
+		}
+		errs, err := t.ApplyBulk(ctx, rowKeys, muts, opts...)
+		if err != nil {
+			return err
+		}
+		for i, rowKey := range rowKeys {
+			if errs != nil && errs[i] != nil {
+				return fmt.Errorf("bigtable: applying row %q: %w", rowKey, errs[i])
+			}
+			if cp != nil {
+				if err := cp.MarkCommitted(rowKey); err != nil {
+					return fmt.Errorf("bigtable: checkpointing row %q: %w", rowKey, err)
+				}
+			}
+		}
+		rowKeys, muts = nil, nil
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case entry, ok := <-rowsCh:
+			if !ok {
+				return flush()
+			}
+			if cp != nil {
+				committed, err := cp.IsCommitted(entry.RowKey)
+				if err != nil {
+					return fmt.Errorf("bigtable: checking checkpoint for row %q: %w", entry.RowKey, err)
+				}
+				if committed {
+					continue
+				}
+			}
+			rowKeys = append(rowKeys, entry.RowKey)
+			muts = append(muts, entry.Mutation)
+			if len(rowKeys) >= bulkStreamChunkSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// FileBulkCheckpoint is a BulkCheckpoint backed by a file of newline
+// separated row keys. Each call to MarkCommitted appends and fsyncs a
+// single line, so a process that is killed mid-stream loses at most the
+// chunk it was in the middle of applying.
+type FileBulkCheckpoint struct {
+	mu        sync.Mutex
+	f         *os.File
+	committed map[string]bool
+}
+
+// NewFileBulkCheckpoint opens (creating if necessary) the checkpoint file at
+// path. If the file already has contents from a previous run, they are
+// loaded so IsCommitted reflects rows committed before a restart. The
+// caller is responsible for calling Close when the checkpoint is no longer
+// needed.
+func NewFileBulkCheckpoint(path string) (*FileBulkCheckpoint, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	committed := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			committed[line] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &FileBulkCheckpoint{f: f, committed: committed}, nil
+}
+
+// IsCommitted reports whether rowKey has already been recorded as committed.
+func (c *FileBulkCheckpoint) IsCommitted(rowKey string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.committed[rowKey], nil
+}
+
+// MarkCommitted appends rowKey to the checkpoint file and fsyncs it before
+// returning, so a crash immediately after MarkCommitted still leaves the
+// checkpoint durable on disk.
+func (c *FileBulkCheckpoint) MarkCommitted(rowKey string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.committed[rowKey] {
+		return nil
+	}
+	if _, err := c.f.WriteString(rowKey + "\n"); err != nil {
+		return err
+	}
+	if err := c.f.Sync(); err != nil {
+		return err
+	}
+	c.committed[rowKey] = true
+	return nil
+}
+
+// Close closes the underlying checkpoint file.
+func (c *FileBulkCheckpoint) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.f.Close()
+}
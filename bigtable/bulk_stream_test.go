@@ -0,0 +1,140 @@
+/*
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bigtable
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	btpb "cloud.google.com/go/bigtable/apiv2/bigtablepb"
+	"google.golang.org/grpc"
+)
+
+// countingServerStream wraps a grpc.ServerStream to count, per row key, how
+// many times a Set mutation for it is received across all
+// MutateRowsRequests sent on the stream.
+type countingServerStream struct {
+	grpc.ServerStream
+	mu      *sync.Mutex
+	applied map[string]int
+}
+
+func (s *countingServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if req, ok := m.(*btpb.MutateRowsRequest); ok {
+		s.mu.Lock()
+		for _, e := range req.Entries {
+			s.applied[string(e.RowKey)]++
+		}
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+func TestApplyBulkStream_ResumesFromCheckpoint(t *testing.T) {
+	old := bulkStreamChunkSize
+	bulkStreamChunkSize = 2
+	defer func() { bulkStreamChunkSize = old }()
+
+	var mu sync.Mutex
+	applied := make(map[string]int)
+	chunkDone := make(chan struct{}, 10)
+
+	tbl, cleanup, err := setupDefaultFakeServer(grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !strings.HasSuffix(info.FullMethod, "MutateRows") {
+			return handler(srv, ss)
+		}
+		err := handler(srv, &countingServerStream{ServerStream: ss, mu: &mu, applied: applied})
+		chunkDone <- struct{}{}
+		return err
+	}))
+	if err != nil {
+		t.Fatalf("fake server setup: %v", err)
+	}
+	defer cleanup()
+
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint")
+	rowKeys := []string{"row1", "row2", "row3", "row4"}
+	newEntries := func() []BulkEntry {
+		var entries []BulkEntry
+		for _, k := range rowKeys {
+			m := NewMutation()
+			m.Set("cf", "col", 1000, []byte("val"))
+			entries = append(entries, BulkEntry{RowKey: k, Mutation: m})
+		}
+		return entries
+	}
+
+	// First run: commits row1 and row2 (one chunk of size 2), then is
+	// "killed" (its context is cancelled) before row3 or row4 ever forms a
+	// complete chunk.
+	cp1, err := NewFileBulkCheckpoint(checkpointPath)
+	if err != nil {
+		t.Fatalf("NewFileBulkCheckpoint: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rowsCh := make(chan BulkEntry)
+	go func() {
+		entries := newEntries()
+		rowsCh <- entries[0]
+		rowsCh <- entries[1]
+		<-chunkDone                       // wait for the first chunk's MutateRows call to finish
+		time.Sleep(50 * time.Millisecond) // let ApplyBulkStream finish checkpointing it
+		rowsCh <- entries[2]
+		time.Sleep(100 * time.Millisecond) // let ApplyBulkStream buffer row3
+		cancel()
+	}()
+	if err := tbl.ApplyBulkStream(ctx, rowsCh, WithCheckpoint(cp1)); err == nil {
+		t.Fatalf("first run: got nil error, want context cancellation")
+	}
+	if err := cp1.Close(); err != nil {
+		t.Fatalf("closing checkpoint after first run: %v", err)
+	}
+
+	// Second run: reopens the same checkpoint file and resumes with the
+	// same full set of entries; row1 and row2 must be skipped.
+	cp2, err := NewFileBulkCheckpoint(checkpointPath)
+	if err != nil {
+		t.Fatalf("reopening checkpoint: %v", err)
+	}
+	defer cp2.Close()
+
+	rowsCh2 := make(chan BulkEntry)
+	go func() {
+		defer close(rowsCh2)
+		for _, e := range newEntries() {
+			rowsCh2 <- e
+		}
+	}()
+	if err := tbl.ApplyBulkStream(context.Background(), rowsCh2, WithCheckpoint(cp2)); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, k := range rowKeys {
+		if got := applied[k]; got != 1 {
+			t.Errorf("row %q: got %d MutateRows Set entries, want exactly 1", k, got)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2016 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bigtable
+
+import (
+	"fmt"
+
+	btpb "cloud.google.com/go/bigtable/apiv2/bigtablepb"
+)
+
+// Filter represents a row filter that can be used in a CheckAndMutateRow
+// request or a ReadRows call.
+type Filter interface {
+	String() string
+	proto() *btpb.RowFilter
+}
+
+type valueFilter struct {
+	re string
+}
+
+// ValueFilter returns a filter that matches cells whose value matches the
+// provided regular expression.
+func ValueFilter(pattern string) Filter {
+	return valueFilter{re: pattern}
+}
+
+func (vf valueFilter) String() string {
+	return fmt.Sprintf("value_match(%q)", vf.re)
+}
+
+func (vf valueFilter) proto() *btpb.RowFilter {
+	return &btpb.RowFilter{Filter: &btpb.RowFilter_ValueRegexFilter{ValueRegexFilter: []byte(vf.re)}}
+}
@@ -0,0 +1,31 @@
+/*
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bigtable
+
+// MetricsProvider supplies the metric recorders used to export client-side
+// metrics for operations performed by a Client. Implementations are expected
+// to be safe for concurrent use.
+type MetricsProvider interface {
+	// metricsProvider is unexported so that MetricsProvider cannot be
+	// implemented outside this package; NoopMetricsProvider is the only
+	// implementation today.
+	metricsProvider()
+}
+
+// NoopMetricsProvider is a MetricsProvider that does not record any metrics.
+type NoopMetricsProvider struct{}
+
+func (NoopMetricsProvider) metricsProvider() {}
@@ -0,0 +1,92 @@
+/*
+Copyright 2015 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bigtable
+
+import (
+	btpb "cloud.google.com/go/bigtable/apiv2/bigtablepb"
+)
+
+// Timestamp is in units of microseconds since 1 January 1970.
+type Timestamp int64
+
+// ServerTime is a special value that can be passed to (*Mutation).Set to
+// indicate that the server's timestamp should be used.
+const ServerTime Timestamp = -1
+
+// A Mutation represents a set of changes for a single row of a table.
+type Mutation struct {
+	ops []*btpb.Mutation
+
+	// Fields used for conditional mutations (NewCondMutation). condFilter is
+	// nil for unconditional mutations.
+	condFilter Filter
+	mTrue      *Mutation
+	mFalse     *Mutation
+}
+
+// NewMutation returns a new mutation.
+func NewMutation() *Mutation {
+	return &Mutation{}
+}
+
+// NewCondMutation returns a conditional mutation. The cond Filter is applied
+// to the row that the mutation is applied to. If the filter matches, mTrue is
+// applied; otherwise, mFalse is applied.
+func NewCondMutation(cond Filter, mTrue, mFalse *Mutation) *Mutation {
+	return &Mutation{condFilter: cond, mTrue: mTrue, mFalse: mFalse}
+}
+
+func (m *Mutation) isConditional() bool { return m.condFilter != nil }
+
+// Set sets a value in a specified column, with the given timestamp.
+// The timestamp will be truncated to millisecond granularity.
+// A timestamp of ServerTime means to use the server timestamp.
+func (m *Mutation) Set(family, column string, ts Timestamp, value []byte) {
+	if ts != ServerTime {
+		ts -= ts % 1000
+	}
+	m.ops = append(m.ops, &btpb.Mutation{
+		Mutation: &btpb.Mutation_SetCell_{SetCell: &btpb.Mutation_SetCell{
+			FamilyName:      family,
+			ColumnQualifier: []byte(column),
+			TimestampMicros: int64(ts),
+			Value:           value,
+		}},
+	})
+}
+
+// DeleteRow deletes the entire row.
+func (m *Mutation) DeleteRow() {
+	m.ops = append(m.ops, &btpb.Mutation{
+		Mutation: &btpb.Mutation_DeleteFromRow_{DeleteFromRow: &btpb.Mutation_DeleteFromRow{}},
+	})
+}
+
+// isIdempotent reports whether every operation making up the mutation is
+// safe to retry without risking a duplicate or out-of-order application,
+// i.e. whether it does not rely on the server choosing a timestamp.
+func (m *Mutation) isIdempotent() bool {
+	if m.isConditional() {
+		return m.mTrue.isIdempotent() && (m.mFalse == nil || m.mFalse.isIdempotent())
+	}
+	for _, op := range m.ops {
+		set, ok := op.Mutation.(*btpb.Mutation_SetCell_)
+		if ok && set.SetCell.TimestampMicros == int64(ServerTime) {
+			return false
+		}
+	}
+	return true
+}
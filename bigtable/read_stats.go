@@ -0,0 +1,101 @@
+/*
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bigtable
+
+// ReadStats summarizes a single ReadRows call, reported via WithReadStats
+// once the call finishes (successfully or not).
+type ReadStats struct {
+	// Attempts is the number of ReadRows RPC attempts made, including the
+	// first.
+	Attempts int
+
+	// RetriedRanges is the number of times the row range was narrowed to
+	// resume after a retryable error partway through the scan.
+	RetriedRanges int
+
+	// RowsDelivered is the cumulative number of rows passed to the
+	// ReadRows callback across all attempts.
+	RowsDelivered int64
+}
+
+// readStatsOption backs WithReadStats.
+type readStatsOption struct {
+	f func(ReadStats)
+}
+
+func (o readStatsOption) readOption(s *readSettings) { s.onStats = o.f }
+
+// WithReadStats returns a ReadOption that reports a ReadStats summary of the
+// call to f once ReadRows returns.
+func WithReadStats(f func(ReadStats)) ReadOption {
+	return readStatsOption{f: f}
+}
+
+// readState tracks the progress of a ReadRows call across retries, in
+// either scan direction, so the row limit and resumption cursor are
+// accounted for identically for forward and reverse scans.
+type readState struct {
+	reverse       bool
+	limit         int64
+	lastRowKey    string
+	rowsDelivered int64
+	attempts      int
+	retriedRanges int
+}
+
+func newReadState(limit int64, reverse bool) *readState {
+	return &readState{limit: limit, reverse: reverse}
+}
+
+// rowsLimit is the number of rows still allowed by LimitRows. It is 0 both
+// when no limit was set and once the limit has been used up.
+func (s *readState) rowsLimit() int64 { return s.limit }
+
+// recordRow accounts for a row delivered to the caller's callback.
+func (s *readState) recordRow(key string) {
+	s.lastRowKey = key
+	s.rowsDelivered++
+	if s.limit > 0 {
+		s.limit--
+	}
+}
+
+// narrow returns arg trimmed to resume after the last row this call has
+// seen (delivered or merely scanned past), and whether the result still has
+// rows left to scan. If no rows have been seen yet, arg is returned
+// unchanged. Reverse scans resume using retainRowsBefore, since they walk
+// the key space downward.
+func (s *readState) narrow(arg RowSet) (RowSet, bool) {
+	if s.lastRowKey == "" {
+		return arg, arg.valid()
+	}
+	s.retriedRanges++
+	var next RowSet
+	if s.reverse {
+		next = arg.retainRowsBefore(s.lastRowKey)
+	} else {
+		next = arg.retainRowsAfter(s.lastRowKey)
+	}
+	return next, next.valid()
+}
+
+func (s *readState) stats() ReadStats {
+	return ReadStats{
+		Attempts:      s.attempts,
+		RetriedRanges: s.retriedRanges,
+		RowsDelivered: s.rowsDelivered,
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2024 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bigtable
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy controls how Apply, ApplyBulk and ReadRows decide whether a
+// failed RPC attempt should be retried, how long to wait before the next
+// attempt, and whether a given Mutation is safe to retry or hedge at all.
+//
+// A RetryPolicy is set for a Client via ClientConfig.RetryPolicy, and may be
+// overridden for an individual call with WithRetryPolicy.
+type RetryPolicy interface {
+	// ShouldRetry reports whether err is retryable, and if so, how long to
+	// wait before the next attempt. attempt is the zero-based index of the
+	// attempt that failed (0 for the first retry decision).
+	ShouldRetry(err error, attempt int) (retry bool, backoff time.Duration)
+
+	// IsIdempotent reports whether m is safe to retry or hedge without risk
+	// of a duplicate or out-of-order application.
+	IsIdempotent(m *Mutation) bool
+}
+
+// backoffForAttempt computes the exponential backoff used by the built-in
+// policies: 10ms, doubling on each attempt, capped at 2s.
+func backoffForAttempt(attempt int) time.Duration {
+	d := 10 * time.Millisecond
+	const max = 2 * time.Second
+	for i := 0; i < attempt; i++ {
+		d *= 2
+		if d >= max {
+			return max
+		}
+	}
+	return d
+}
+
+// defaultPolicy implements the client's built-in retry behavior: the codes
+// and codes.Internal messages in retryableInternalErrMsgs are retryable,
+// google.rpc.RetryInfo details are honored when present, and a Mutation is
+// idempotent unless it (or, for a conditional mutation, either branch of
+// it) relies on the server choosing a timestamp.
+type defaultPolicy struct{}
+
+func (defaultPolicy) ShouldRetry(err error, attempt int) (bool, time.Duration) {
+	if !isRetryableError(err) {
+		return false, 0
+	}
+	if d, ok := retryInfoDelay(err); ok {
+		return true, d
+	}
+	return true, backoffForAttempt(attempt)
+}
+
+func (defaultPolicy) IsIdempotent(m *Mutation) bool { return m.isIdempotent() }
+
+// DefaultRetryPolicy is the client's built-in retry behavior: it retries
+// codes.Unavailable and codes.Aborted, retries codes.Internal only for a
+// known set of transient error messages, and honors any google.rpc.RetryInfo
+// the server attaches to an error.
+var DefaultRetryPolicy RetryPolicy = defaultPolicy{}
+
+// aggressivePolicy retries a wider set of codes than DefaultRetryPolicy and
+// backs off half as long, for callers who would rather hammer a struggling
+// backend than fail fast. It treats every Mutation as idempotent, so it
+// should only be used where duplicate application is known to be harmless.
+type aggressivePolicy struct{}
+
+func (aggressivePolicy) ShouldRetry(err error, attempt int) (bool, time.Duration) {
+	st, ok := status.FromError(err)
+	if !ok {
+		return false, 0
+	}
+	switch st.Code() {
+	case codes.Unavailable, codes.Aborted, codes.Internal, codes.DeadlineExceeded, codes.ResourceExhausted:
+		if d, ok := retryInfoDelay(err); ok {
+			return true, d
+		}
+		return true, backoffForAttempt(attempt) / 2
+	default:
+		return false, 0
+	}
+}
+
+func (aggressivePolicy) IsIdempotent(*Mutation) bool { return true }
+
+// AggressiveRetryPolicy retries more status codes than DefaultRetryPolicy,
+// backs off more quickly, and treats every Mutation as idempotent. It
+// trades correctness for availability and should only be used when the
+// caller has verified that duplicate mutation application is acceptable.
+var AggressiveRetryPolicy RetryPolicy = aggressivePolicy{}
+
+// noRetryPolicy never retries and never considers a Mutation idempotent,
+// disabling both retries and hedging entirely.
+type noRetryPolicy struct{}
+
+func (noRetryPolicy) ShouldRetry(error, int) (bool, time.Duration) { return false, 0 }
+func (noRetryPolicy) IsIdempotent(*Mutation) bool                  { return false }
+
+// NoRetryPolicy disables retries (and hedging) entirely: the first error
+// from any attempt is returned to the caller.
+var NoRetryPolicy RetryPolicy = noRetryPolicy{}
+
+// clientRetryPolicy is the RetryPolicy installed on a Client that didn't
+// set ClientConfig.RetryPolicy. It behaves like DefaultRetryPolicy, except
+// that it additionally honors the client's disableRetryInfo setting, which
+// predates RetryPolicy and is configured independently via ClientConfig or
+// the DISABLE_RETRY_INFO environment variable.
+type clientRetryPolicy struct {
+	c *Client
+}
+
+func (p clientRetryPolicy) ShouldRetry(err error, attempt int) (bool, time.Duration) {
+	if !isRetryableError(err) {
+		return false, 0
+	}
+	if !p.c.disableRetryInfo {
+		if d, ok := retryInfoDelay(err); ok {
+			return true, d
+		}
+	}
+	return true, backoffForAttempt(attempt)
+}
+
+func (clientRetryPolicy) IsIdempotent(m *Mutation) bool { return m.isIdempotent() }
+
+// retryPolicyOption overrides the RetryPolicy used for a single call,
+// following the same dual ApplyOption/ReadOption pattern as attemptOption.
+type retryPolicyOption struct {
+	policy RetryPolicy
+}
+
+func (o retryPolicyOption) applyOption(s *applySettings) { s.retryPolicy = o.policy }
+func (o retryPolicyOption) readOption(s *readSettings)   { s.retryPolicy = o.policy }
+
+// WithRetryPolicy returns an option that overrides the Table's RetryPolicy
+// for a single call to Apply, ApplyBulk or ReadRows.
+func WithRetryPolicy(policy RetryPolicy) interface {
+	ApplyOption
+	ReadOption
+} {
+	return retryPolicyOption{policy: policy}
+}
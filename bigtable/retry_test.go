@@ -18,6 +18,7 @@ package bigtable
 import (
 	"context"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -26,10 +27,12 @@ import (
 	"cloud.google.com/go/internal/testutil"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	rpcpb "google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
@@ -391,6 +394,36 @@ func TestRetainRowsAfter(t *testing.T) {
 	}
 }
 
+func TestAggressiveRetryPolicy(t *testing.T) {
+	retryableCodes := []codes.Code{codes.Unavailable, codes.Aborted, codes.Internal, codes.DeadlineExceeded, codes.ResourceExhausted}
+	for _, c := range retryableCodes {
+		if retry, _ := AggressiveRetryPolicy.ShouldRetry(status.Errorf(c, ""), 0); !retry {
+			t.Errorf("ShouldRetry(%v): got false, want true", c)
+		}
+	}
+
+	nonRetryableCodes := []codes.Code{codes.OK, codes.FailedPrecondition, codes.NotFound, codes.InvalidArgument}
+	for _, c := range nonRetryableCodes {
+		if retry, _ := AggressiveRetryPolicy.ShouldRetry(status.Errorf(c, ""), 0); retry {
+			t.Errorf("ShouldRetry(%v): got true, want false", c)
+		}
+	}
+
+	// Absent a RetryInfo hint, the backoff is half of backoffForAttempt,
+	// i.e. half of what DefaultRetryPolicy would wait.
+	if _, backoff := AggressiveRetryPolicy.ShouldRetry(status.Errorf(codes.Unavailable, ""), 2); backoff != backoffForAttempt(2)/2 {
+		t.Errorf("backoff: got %v, want %v", backoff, backoffForAttempt(2)/2)
+	}
+
+	// AggressiveRetryPolicy treats every Mutation as idempotent, even one
+	// that relies on the server choosing a timestamp.
+	m := NewMutation()
+	m.Set("cf", "col", ServerTime, []byte("val"))
+	if !AggressiveRetryPolicy.IsIdempotent(m) {
+		t.Errorf("IsIdempotent(ServerTime mutation): got false, want true")
+	}
+}
+
 func TestRetryReadRows(t *testing.T) {
 	ctx := context.Background()
 
@@ -592,6 +625,77 @@ func TestRetryReverseReadRows(t *testing.T) {
 	}
 }
 
+func TestRetryReverseReadRowsLimit(t *testing.T) {
+	ctx := context.Background()
+
+	// Intercept requests and delegate to an interceptor defined by the test case
+	errCount := 0
+	var f func(grpc.ServerStream) error
+	errInjector := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if strings.HasSuffix(info.FullMethod, "ReadRows") {
+			return f(ss)
+		}
+		return handler(ctx, ss)
+	}
+
+	tbl, cleanup, err := setupDefaultFakeServer(grpc.StreamInterceptor(errInjector))
+	defer cleanup()
+	if err != nil {
+		t.Fatalf("fake server setup: %v", err)
+	}
+
+	initialRowLimit := int64(3)
+
+	errCount = 0
+	f = func(ss grpc.ServerStream) error {
+		var err error
+		req := new(btpb.ReadRowsRequest)
+		must(ss.RecvMsg(req))
+		switch errCount {
+		case 0:
+			if want, got := initialRowLimit, req.RowsLimit; want != got {
+				t.Errorf("RowsLimit: got %v, want %v", got, want)
+			}
+			if want, got := "z", string(req.Rows.RowRanges[0].GetEndKeyClosed()); want != got {
+				t.Errorf("first attempt end key: got %q, want %q", got, want)
+			}
+			must(writeReadRowsResponse(ss, "g", "f"))
+			err = status.Errorf(codes.Unavailable, "")
+		case 1:
+			if want, got := initialRowLimit-2, req.RowsLimit; want != got {
+				t.Errorf("RowsLimit: got %v, want %v", got, want)
+			}
+			if want, got := "f", string(req.Rows.RowRanges[0].GetEndKeyOpen()); want != got {
+				t.Errorf("retried end key: got %q, want %q", got, want)
+			}
+			must(writeReadRowsResponse(ss, "e"))
+			err = nil
+		}
+		errCount++
+		return err
+	}
+
+	var got []string
+	var stats ReadStats
+	must(tbl.ReadRows(ctx, NewClosedRange("a", "z"), func(r Row) bool {
+		got = append(got, r.Key())
+		return true
+	}, ReverseScan(), LimitRows(initialRowLimit), WithReadStats(func(s ReadStats) { stats = s })))
+	want := []string{"g", "f", "e"}
+	if !testutil.Equal(got, want) {
+		t.Errorf("reverse limited retry integration: got %v, want %v", got, want)
+	}
+	if stats.Attempts != 2 {
+		t.Errorf("ReadStats.Attempts: got %d, want 2", stats.Attempts)
+	}
+	if stats.RetriedRanges != 1 {
+		t.Errorf("ReadStats.RetriedRanges: got %d, want 1", stats.RetriedRanges)
+	}
+	if stats.RowsDelivered != 3 {
+		t.Errorf("ReadStats.RowsDelivered: got %d, want 3", stats.RowsDelivered)
+	}
+}
+
 func TestRetryOptionSelection(t *testing.T) {
 	ctx := context.Background()
 	project := "test-project"
@@ -622,6 +726,20 @@ func TestRetryOptionSelection(t *testing.T) {
 			t.Errorf("client.disableRetryInfo got: false, want: true")
 		}
 	})
+
+	t.Run("CustomRetryPolicy", func(t *testing.T) {
+		config := disableMetricsConfig
+		config.RetryPolicy = NoRetryPolicy
+		client, err := NewClientWithConfig(ctx, project, instance, config)
+		if err != nil {
+			t.Fatalf("NewClientWithConfig: %v", err)
+		}
+		defer client.Close()
+
+		if client.retryPolicy != NoRetryPolicy {
+			t.Errorf("client.retryPolicy got: %v, want: NoRetryPolicy", client.retryPolicy)
+		}
+	})
 }
 
 func writeReadRowsResponse(ss grpc.ServerStream, rowKeys ...string) error {
@@ -642,3 +760,407 @@ func must(err error) {
 		panic(err)
 	}
 }
+
+// statusWithRetryInfo returns a status error carrying a google.rpc.RetryInfo
+// detail instructing the client to wait delay before retrying.
+func statusWithRetryInfo(code codes.Code, delay time.Duration) error {
+	st := status.New(code, "")
+	st, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)})
+	if err != nil {
+		panic(err)
+	}
+	return st.Err()
+}
+
+func TestRetryApply_HonorsRetryInfo(t *testing.T) {
+	ctx := context.Background()
+
+	errCount := 0
+	errInjector := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasSuffix(info.FullMethod, "MutateRow") && errCount < 1 {
+			errCount++
+			return nil, statusWithRetryInfo(codes.Unavailable, 300*time.Millisecond)
+		}
+		return handler(ctx, req)
+	}
+	tbl, cleanup, err := setupDefaultFakeServer(grpc.UnaryInterceptor(errInjector))
+	if err != nil {
+		t.Fatalf("fake server setup: %v", err)
+	}
+	defer cleanup()
+
+	mut := NewMutation()
+	mut.Set("cf", "col", 1000, []byte("val"))
+	start := time.Now()
+	if err := tbl.Apply(ctx, "row1", mut); err != nil {
+		t.Fatalf("applying mutation with RetryInfo: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("RetryInfo delay not honored: retried after %v, want at least 250ms", elapsed)
+	}
+}
+
+func TestRetryApply_DisableRetryInfo(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("DISABLE_RETRY_INFO", "1")
+
+	errCount := 0
+	errInjector := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasSuffix(info.FullMethod, "MutateRow") && errCount < 1 {
+			errCount++
+			return nil, statusWithRetryInfo(codes.Unavailable, 2*time.Second)
+		}
+		return handler(ctx, req)
+	}
+	srv, err := bttest.NewServer("localhost:0", grpc.UnaryInterceptor(errInjector))
+	if err != nil {
+		t.Fatalf("fake server setup: %v", err)
+	}
+	conn, err := grpc.Dial(srv.Addr, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	client, err := NewClientWithConfig(ctx, "client", "instance", ClientConfig{MetricsProvider: NoopMetricsProvider{}}, option.WithGRPCConn(conn), option.WithGRPCDialOption(grpc.WithBlock()))
+	if err != nil {
+		t.Fatalf("NewClientWithConfig: %v", err)
+	}
+	defer client.Close()
+	if !client.disableRetryInfo {
+		t.Fatalf("client.disableRetryInfo got: false, want: true")
+	}
+	adminClient, err := NewAdminClient(ctx, "client", "instance", option.WithGRPCConn(conn), option.WithGRPCDialOption(grpc.WithBlock()))
+	if err != nil {
+		t.Fatalf("NewAdminClient: %v", err)
+	}
+	defer adminClient.Close()
+	must(adminClient.CreateTable(ctx, "table"))
+	must(adminClient.CreateColumnFamily(ctx, "table", "cf"))
+	defer srv.Close()
+
+	tbl := client.Open("table")
+	mut := NewMutation()
+	mut.Set("cf", "col", 1000, []byte("val"))
+	start := time.Now()
+	if err := tbl.Apply(ctx, "row1", mut); err != nil {
+		t.Fatalf("applying mutation with RetryInfo disabled: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("RetryInfo should have been ignored: retried after %v, want well under 2s", elapsed)
+	}
+}
+
+func TestApply_Hedging(t *testing.T) {
+	ctx := context.Background()
+
+	var callCount int32
+	errInjector := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasSuffix(info.FullMethod, "MutateRow") {
+			n := atomic.AddInt32(&callCount, 1)
+			if n == 1 {
+				// The primary attempt is slow enough that the hedge fires.
+				time.Sleep(200 * time.Millisecond)
+			}
+		}
+		return handler(ctx, req)
+	}
+	tbl, cleanup, err := setupDefaultFakeServer(grpc.UnaryInterceptor(errInjector))
+	if err != nil {
+		t.Fatalf("fake server setup: %v", err)
+	}
+	defer cleanup()
+
+	mut := NewMutation()
+	mut.Set("cf", "col", 1000, []byte("val"))
+	start := time.Now()
+	if err := tbl.Apply(ctx, "row1", mut, WithHedging(HedgingPolicy{Delay: 20 * time.Millisecond})); err != nil {
+		t.Fatalf("applying hedged mutation: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("hedge did not win the race: took %v, want well under the 200ms slow attempt", elapsed)
+	}
+	if n := atomic.LoadInt32(&callCount); n < 2 {
+		t.Errorf("hedge did not fire a second attempt: got %d MutateRow calls, want at least 2", n)
+	}
+
+	row, err := tbl.ReadRow(ctx, "row1")
+	if err != nil {
+		t.Fatalf("reading back hedged row: %v", err)
+	}
+	if row == nil {
+		t.Fatalf("hedged mutation: could not read back row")
+	}
+}
+
+func TestApply_HedgingDisabledForNonIdempotent(t *testing.T) {
+	ctx := context.Background()
+
+	var callCount int32
+	errInjector := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasSuffix(info.FullMethod, "MutateRow") {
+			atomic.AddInt32(&callCount, 1)
+			time.Sleep(200 * time.Millisecond)
+		}
+		return handler(ctx, req)
+	}
+	tbl, cleanup, err := setupDefaultFakeServer(grpc.UnaryInterceptor(errInjector))
+	if err != nil {
+		t.Fatalf("fake server setup: %v", err)
+	}
+	defer cleanup()
+
+	// ServerTime makes the mutation non-idempotent, so hedging must not
+	// duplicate it even though it's slow enough to trigger the delay.
+	mut := NewMutation()
+	mut.Set("cf", "col", ServerTime, []byte("val"))
+	if err := tbl.Apply(ctx, "row1", mut, WithHedging(HedgingPolicy{Delay: 20 * time.Millisecond})); err != nil {
+		t.Fatalf("applying non-idempotent mutation: %v", err)
+	}
+	if n := atomic.LoadInt32(&callCount); n != 1 {
+		t.Errorf("non-idempotent mutation was hedged: got %d MutateRow calls, want exactly 1", n)
+	}
+}
+
+func TestApply_NoRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	errCount := 0
+	errInjector := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasSuffix(info.FullMethod, "MutateRow") && errCount < 1 {
+			errCount++
+			return nil, status.Errorf(codes.Unavailable, "")
+		}
+		return handler(ctx, req)
+	}
+	tbl, cleanup, err := setupDefaultFakeServer(grpc.UnaryInterceptor(errInjector))
+	if err != nil {
+		t.Fatalf("fake server setup: %v", err)
+	}
+	defer cleanup()
+
+	mut := NewMutation()
+	mut.Set("cf", "col", 1000, []byte("val"))
+	if err := tbl.Apply(ctx, "row1", mut, WithRetryPolicy(NoRetryPolicy)); err == nil {
+		t.Errorf("applying with NoRetryPolicy: got nil error, want the first attempt's error surfaced")
+	}
+	if errCount != 1 {
+		t.Errorf("applying with NoRetryPolicy: got %d attempts, want exactly 1", errCount)
+	}
+}
+
+// alwaysIdempotentPolicy wraps DefaultRetryPolicy but treats every Mutation
+// as idempotent, regardless of whether it uses ServerTime.
+type alwaysIdempotentPolicy struct{}
+
+func (alwaysIdempotentPolicy) ShouldRetry(err error, attempt int) (bool, time.Duration) {
+	return DefaultRetryPolicy.ShouldRetry(err, attempt)
+}
+
+func (alwaysIdempotentPolicy) IsIdempotent(*Mutation) bool { return true }
+
+func TestApply_CustomRetryPolicyIdempotency(t *testing.T) {
+	ctx := context.Background()
+
+	var callCount int32
+	errInjector := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasSuffix(info.FullMethod, "MutateRow") {
+			atomic.AddInt32(&callCount, 1)
+			time.Sleep(200 * time.Millisecond)
+		}
+		return handler(ctx, req)
+	}
+	tbl, cleanup, err := setupDefaultFakeServer(grpc.UnaryInterceptor(errInjector))
+	if err != nil {
+		t.Fatalf("fake server setup: %v", err)
+	}
+	defer cleanup()
+
+	// ServerTime makes this mutation non-idempotent under DefaultRetryPolicy,
+	// but alwaysIdempotentPolicy overrides that, so hedging should fire.
+	mut := NewMutation()
+	mut.Set("cf", "col", ServerTime, []byte("val"))
+	err = tbl.Apply(ctx, "row1", mut,
+		WithHedging(HedgingPolicy{Delay: 20 * time.Millisecond}),
+		WithRetryPolicy(alwaysIdempotentPolicy{}))
+	if err != nil {
+		t.Fatalf("applying with custom retry policy: %v", err)
+	}
+	if n := atomic.LoadInt32(&callCount); n < 2 {
+		t.Errorf("custom IsIdempotent override was not honored: got %d MutateRow calls, want at least 2", n)
+	}
+}
+
+func TestApply_AttemptTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	var callCount int32
+	errInjector := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasSuffix(info.FullMethod, "MutateRow") {
+			n := atomic.AddInt32(&callCount, 1)
+			if n == 1 {
+				// Outlives the attempt timeout but well within the overall,
+				// deadline-less ctx, so only the timeout can cut it off.
+				time.Sleep(200 * time.Millisecond)
+			}
+		}
+		return handler(ctx, req)
+	}
+	tbl, cleanup, err := setupDefaultFakeServer(grpc.UnaryInterceptor(errInjector))
+	if err != nil {
+		t.Fatalf("fake server setup: %v", err)
+	}
+	defer cleanup()
+
+	mut := NewMutation()
+	mut.Set("cf", "col", 1000, []byte("val"))
+	start := time.Now()
+	// AggressiveRetryPolicy is used because the attempt timeout surfaces as
+	// codes.DeadlineExceeded, which DefaultRetryPolicy does not retry.
+	err = tbl.Apply(ctx, "row1", mut,
+		WithAttemptTimeout(20*time.Millisecond),
+		WithRetryPolicy(AggressiveRetryPolicy))
+	if err != nil {
+		t.Fatalf("applying with attempt timeout: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("attempt timeout did not bound the first attempt: took %v, want well under the 200ms slow attempt", elapsed)
+	}
+	if n := atomic.LoadInt32(&callCount); n < 2 {
+		t.Errorf("attempt timeout did not trigger a retry: got %d MutateRow calls, want at least 2", n)
+	}
+}
+
+func TestApplyBulk_Hedging(t *testing.T) {
+	ctx := context.Background()
+
+	var callCount int32
+	errInjector := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if strings.HasSuffix(info.FullMethod, "MutateRows") {
+			n := atomic.AddInt32(&callCount, 1)
+			if n == 1 {
+				// The primary attempt's response is slow enough that the
+				// hedge fires.
+				time.Sleep(200 * time.Millisecond)
+			}
+		}
+		return handler(srv, ss)
+	}
+	tbl, cleanup, err := setupDefaultFakeServer(grpc.StreamInterceptor(errInjector))
+	if err != nil {
+		t.Fatalf("fake server setup: %v", err)
+	}
+	defer cleanup()
+
+	m1 := NewMutation()
+	m1.Set("cf", "col", 1000, []byte("val1"))
+	m2 := NewMutation()
+	m2.Set("cf", "col", 1000, []byte("val2"))
+	start := time.Now()
+	errs, err := tbl.ApplyBulk(ctx, []string{"row1", "row2"}, []*Mutation{m1, m2}, WithHedging(HedgingPolicy{Delay: 20 * time.Millisecond}))
+	if err != nil {
+		t.Fatalf("applying hedged bulk mutation: %v", err)
+	}
+	for i, e := range errs {
+		if e != nil {
+			t.Errorf("entry %d: got error %v, want nil", i, e)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("hedge did not win the race: took %v, want well under the 200ms slow attempt", elapsed)
+	}
+	if n := atomic.LoadInt32(&callCount); n < 2 {
+		t.Errorf("hedge did not fire a second attempt: got %d MutateRows calls, want at least 2", n)
+	}
+
+	row, err := tbl.ReadRow(ctx, "row1")
+	if err != nil {
+		t.Fatalf("reading back hedged row: %v", err)
+	}
+	if row == nil {
+		t.Fatalf("hedged bulk mutation: could not read back row")
+	}
+}
+
+func TestApplyBulk_HedgingDisabledForNonIdempotent(t *testing.T) {
+	ctx := context.Background()
+
+	var callCount int32
+	errInjector := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if strings.HasSuffix(info.FullMethod, "MutateRows") {
+			atomic.AddInt32(&callCount, 1)
+			var req btpb.MutateRowsRequest
+			must(ss.RecvMsg(&req))
+			time.Sleep(200 * time.Millisecond)
+			return writeMutateRowsResponse(ss, codes.OK, codes.OK)
+		}
+		return handler(ctx, ss)
+	}
+	tbl, cleanup, err := setupDefaultFakeServer(grpc.StreamInterceptor(errInjector))
+	if err != nil {
+		t.Fatalf("fake server setup: %v", err)
+	}
+	defer cleanup()
+
+	// ServerTime makes m2 non-idempotent, so the whole batch must not be
+	// hedged even though it's slow enough to trigger the delay: a
+	// duplicate, concurrently-applied write could otherwise pick a
+	// different server timestamp for m2 on each attempt.
+	m1 := NewMutation()
+	m1.Set("cf", "col", 1000, []byte("val1"))
+	m2 := NewMutation()
+	m2.Set("cf", "col", ServerTime, []byte("val2"))
+	errs, err := tbl.ApplyBulk(ctx, []string{"row1", "row2"}, []*Mutation{m1, m2}, WithHedging(HedgingPolicy{Delay: 20 * time.Millisecond}))
+	if err != nil {
+		t.Fatalf("applying non-idempotent bulk mutation: %v", err)
+	}
+	for i, e := range errs {
+		if e != nil {
+			t.Errorf("entry %d: got error %v, want nil", i, e)
+		}
+	}
+	if n := atomic.LoadInt32(&callCount); n != 1 {
+		t.Errorf("non-idempotent batch was hedged: got %d MutateRows calls, want exactly 1", n)
+	}
+}
+
+func TestReadRows_Hedging(t *testing.T) {
+	ctx := context.Background()
+
+	var callCount int32
+	errInjector := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if strings.HasSuffix(info.FullMethod, "ReadRows") {
+			n := atomic.AddInt32(&callCount, 1)
+			if n == 1 {
+				// The primary attempt is slow enough that the hedge fires.
+				time.Sleep(200 * time.Millisecond)
+			}
+		}
+		return handler(srv, ss)
+	}
+	tbl, cleanup, err := setupDefaultFakeServer(grpc.StreamInterceptor(errInjector))
+	if err != nil {
+		t.Fatalf("fake server setup: %v", err)
+	}
+	defer cleanup()
+
+	m := NewMutation()
+	m.Set("cf", "col", 1000, []byte("val"))
+	must(tbl.Apply(ctx, "row1", m))
+
+	start := time.Now()
+	var got []string
+	if err := tbl.ReadRows(ctx, NewRange("a", "z"), func(r Row) bool {
+		got = append(got, r.Key())
+		return true
+	}, WithHedging(HedgingPolicy{Delay: 20 * time.Millisecond})); err != nil {
+		t.Fatalf("hedged ReadRows: %v", err)
+	}
+	if want := []string{"row1"}; !testutil.Equal(got, want) {
+		t.Errorf("hedged ReadRows: got %v, want %v", got, want)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("hedge did not win the race: took %v, want well under the 200ms slow attempt", elapsed)
+	}
+	if n := atomic.LoadInt32(&callCount); n < 2 {
+		t.Errorf("hedge did not fire a second attempt: got %d ReadRows calls, want at least 2", n)
+	}
+}
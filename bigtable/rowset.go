@@ -0,0 +1,201 @@
+/*
+Copyright 2015 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bigtable
+
+import (
+	btpb "cloud.google.com/go/bigtable/apiv2/bigtablepb"
+)
+
+// RowSet is a set of rows to be read. It is satisfied by RowList, RowRange
+// and RowRangeList. The serialized form of a RowSet is determined by its
+// concrete type.
+type RowSet interface {
+	proto() *btpb.RowSet
+
+	// retainRowsAfter returns a new RowSet that does not include the
+	// given row key or any row key lexically less than it. It is used by
+	// the client to resume a ReadRows call after a retryable error.
+	retainRowsAfter(lastRowKey string) RowSet
+
+	// retainRowsBefore returns a new RowSet that does not include the
+	// given row key or any row key lexically greater than it. It is used
+	// by the client to resume a reverse ReadRows call after a retryable
+	// error.
+	retainRowsBefore(lastRowKey string) RowSet
+
+	// valid reports whether the RowSet is non-empty.
+	valid() bool
+}
+
+// RowList is a sequence of row keys.
+type RowList []string
+
+func (r RowList) proto() *btpb.RowSet {
+	keys := make([][]byte, len(r))
+	for i, row := range r {
+		keys[i] = []byte(row)
+	}
+	return &btpb.RowSet{RowKeys: keys}
+}
+
+func (r RowList) retainRowsAfter(lastRowKey string) RowSet {
+	var retryKeys RowList
+	for _, key := range r {
+		if key > lastRowKey {
+			retryKeys = append(retryKeys, key)
+		}
+	}
+	return retryKeys
+}
+
+func (r RowList) retainRowsBefore(lastRowKey string) RowSet {
+	var retryKeys RowList
+	for _, key := range r {
+		if key < lastRowKey {
+			retryKeys = append(retryKeys, key)
+		}
+	}
+	return retryKeys
+}
+
+func (r RowList) valid() bool { return len(r) > 0 }
+
+// A RowRange describes a range of rows between the start and end key.
+// Start and end keys may be rewritten by successive calls to
+// retainRowsAfter, so the exported constructors should be used to create
+// one.
+type RowRange struct {
+	start      string
+	end        string // "" means end of table
+	startBound boundType
+	endBound   boundType
+}
+
+type boundType int
+
+const (
+	boundClosed boundType = iota
+	boundOpen
+)
+
+// NewRange returns the row range [begin, end).
+func NewRange(begin, end string) RowRange {
+	return RowRange{start: begin, end: end, startBound: boundClosed, endBound: boundOpen}
+}
+
+// NewOpenRange returns the row range (begin, end).
+func NewOpenRange(begin, end string) RowRange {
+	return RowRange{start: begin, end: end, startBound: boundOpen, endBound: boundOpen}
+}
+
+// NewClosedRange returns the row range [begin, end].
+func NewClosedRange(begin, end string) RowRange {
+	return RowRange{start: begin, end: end, startBound: boundClosed, endBound: boundClosed}
+}
+
+func (r RowRange) proto() *btpb.RowSet {
+	rr := &btpb.RowRange{}
+	if r.start != "" || r.startBound == boundOpen {
+		if r.startBound == boundOpen {
+			rr.StartKey = &btpb.RowRange_StartKeyOpen{StartKeyOpen: []byte(r.start)}
+		} else {
+			rr.StartKey = &btpb.RowRange_StartKeyClosed{StartKeyClosed: []byte(r.start)}
+		}
+	}
+	if r.end != "" {
+		if r.endBound == boundOpen {
+			rr.EndKey = &btpb.RowRange_EndKeyOpen{EndKeyOpen: []byte(r.end)}
+		} else {
+			rr.EndKey = &btpb.RowRange_EndKeyClosed{EndKeyClosed: []byte(r.end)}
+		}
+	}
+	return &btpb.RowSet{RowRanges: []*btpb.RowRange{rr}}
+}
+
+// retainRowsAfter narrows the start of the range to just after lastRowKey.
+func (r RowRange) retainRowsAfter(lastRowKey string) RowSet {
+	if lastRowKey == "" {
+		return r
+	}
+	return RowRange{start: lastRowKey, end: r.end, startBound: boundOpen, endBound: r.endBound}
+}
+
+// retainRowsBefore narrows the end of the range to just before
+// lastRowKey. It is used when resuming a reverse scan.
+func (r RowRange) retainRowsBefore(lastRowKey string) RowSet {
+	if lastRowKey == "" {
+		return r
+	}
+	return RowRange{start: r.start, end: lastRowKey, startBound: r.startBound, endBound: boundOpen}
+}
+
+func (r RowRange) valid() bool {
+	if r.start == "" || r.end == "" {
+		return true
+	}
+	if r.start == r.end {
+		return r.startBound == boundClosed && r.endBound == boundClosed
+	}
+	return r.start < r.end
+}
+
+// RowRangeList is a sequence of RowRanges representing the union of the
+// ranges.
+type RowRangeList []RowRange
+
+func (r RowRangeList) proto() *btpb.RowSet {
+	set := &btpb.RowSet{}
+	for _, rr := range r {
+		set.RowRanges = append(set.RowRanges, rr.proto().RowRanges...)
+	}
+	return set
+}
+
+func (r RowRangeList) retainRowsAfter(lastRowKey string) RowSet {
+	if lastRowKey == "" {
+		return r
+	}
+	var ranges RowRangeList
+	for _, rr := range r {
+		if rr.end != "" && rr.end <= lastRowKey {
+			continue // fully consumed
+		}
+		if rr.start <= lastRowKey {
+			rr = rr.retainRowsAfter(lastRowKey).(RowRange)
+		}
+		ranges = append(ranges, rr)
+	}
+	return ranges
+}
+
+func (r RowRangeList) retainRowsBefore(lastRowKey string) RowSet {
+	if lastRowKey == "" {
+		return r
+	}
+	var ranges RowRangeList
+	for _, rr := range r {
+		if rr.start != "" && rr.start >= lastRowKey {
+			continue // fully consumed by the reverse scan
+		}
+		if rr.end == "" || rr.end >= lastRowKey {
+			rr = rr.retainRowsBefore(lastRowKey).(RowRange)
+		}
+		ranges = append(ranges, rr)
+	}
+	return ranges
+}
+
+func (r RowRangeList) valid() bool { return len(r) > 0 }